@@ -3,6 +3,9 @@
 package autoupdate
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 	"time"
@@ -15,6 +18,10 @@ import (
 
 var log = golog.LoggerFor("autoupdate")
 
+// errVersionNotSet is returned by WatchContext when SetVersion hasn't been
+// called yet.
+var errVersionNotSet = errors.New(`autoupdate: You must set the executable version in order to watch for updates!`)
+
 // Making sure AutoUpdate and Patch satisfy AutoUpdater and Patcher.
 var (
 	_ = AutoUpdater(&AutoUpdate{})
@@ -29,6 +36,17 @@ var (
 type Config struct {
 	URL       string
 	PublicKey []byte
+	// Rollout governs whether a discovered update is actually applied. The
+	// zero value is AutoRollout(), i.e. always apply the newest version.
+	Rollout RolloutPolicy
+	// PatchTypes lists the patch formats this client can apply, in
+	// preference order. Defaults to bsdiff, zstd-patch, none.
+	PatchTypes []string
+	// FullBinaryURL is a template (with {version}, {os}, {arch}
+	// placeholders) used to fetch a full signed binary when applying a patch
+	// fails mid-stream. The server's choice of patch format is not read, so
+	// it is not used when the server declines to patch.
+	FullBinaryURL string
 }
 
 // SetProxy sets the proxy to use.
@@ -50,6 +68,19 @@ func SetProxy(proxyAddr string) {
 type AutoUpdate struct {
 	*Config
 	v string
+	// Source is where update metadata comes from. It defaults to the
+	// URL-based check.Result protocol but can be swapped out, e.g. for a
+	// GitHubReleasesSource.
+	Source Source
+	// Scheduler decides how long to wait between checks. It defaults to a
+	// FixedIntervalScheduler using sleepTime.
+	Scheduler Scheduler
+	// Events, if set, receives a structured record of each step of the
+	// update loop (check-started, check-failed, update-available,
+	// patch-applied, patch-failed, policy-blocked, full-binary-fallback).
+	// Sends never block: an event is dropped if no receiver is ready and the
+	// channel has no free buffer. It is never closed by AutoUpdate.
+	Events chan<- Event
 	// When a patch has been applied, the patch's version will be sent to
 	// UpdatedTo.
 	UpdatedTo chan string
@@ -60,6 +91,8 @@ func New(cfg *Config) *AutoUpdate {
 	a := &AutoUpdate{
 		UpdatedTo: make(chan string),
 		Config:    cfg,
+		Source:    &urlSource{cfg: cfg},
+		Scheduler: NewFixedIntervalScheduler(sleepTime),
 	}
 	return a
 }
@@ -83,28 +116,36 @@ func (a *AutoUpdate) Version() string {
 	return a.v
 }
 
-// check uses go-update to look for updates.
-func (a *AutoUpdate) check() (res *check.Result, err error) {
-	var up *update.Update
-
-	param := check.Params{
-		AppVersion: a.Version(),
-	}
+// channelSetter and archSetter are implemented by Sources that support
+// multiple release channels or target platforms, currently ManifestSource.
+type channelSetter interface {
+	SetChannel(channel string)
+}
 
-	up = update.New().ApplyPatch(update.PATCHTYPE_BSDIFF)
+type archSetter interface {
+	SetArch(os, arch string)
+}
 
-	if _, err = up.VerifySignatureWithPEM(a.PublicKey); err != nil {
-		return nil, err
+// SetChannel switches which release channel (e.g. "stable", "beta",
+// "nightly") this AutoUpdate looks for updates on, if its Source supports
+// channels. It's a no-op otherwise.
+func (a *AutoUpdate) SetChannel(channel string) {
+	if s, ok := a.Source.(channelSetter); ok {
+		s.SetChannel(channel)
 	}
+}
 
-	if res, err = param.CheckForUpdate(a.URL, up); err != nil {
-		if err == check.NoUpdateAvailable {
-			return nil, nil
-		}
-		return nil, err
+// SetArch overrides the platform this AutoUpdate requests updates for, if
+// its Source supports it. It's a no-op otherwise.
+func (a *AutoUpdate) SetArch(os, arch string) {
+	if s, ok := a.Source.(archSetter); ok {
+		s.SetArch(os, arch)
 	}
+}
 
-	return res, nil
+// check asks a.Source for the latest update.
+func (a *AutoUpdate) check() (res *check.Result, err error) {
+	return a.Source.Check(a.Version())
 }
 
 // Query checks if a new version is available and returns a Patcher.
@@ -121,47 +162,130 @@ func (a *AutoUpdate) Query() (Patcher, error) {
 		return &Patch{}, nil
 	}
 
+	if allowed, reason := a.Rollout.evaluate(a.Version(), res.Version); !allowed {
+		return nil, &TooNewError{Current: a.Version(), Remote: res.Version, Policy: a.Rollout, Reason: reason}
+	}
+
 	return &Patch{res: res, v: res.Version}, nil
 }
 
-func (a *AutoUpdate) loop() {
+// applyWithFallback applies patch and, if that fails, retries once with the
+// full signed binary for the same version fetched from Config.FullBinaryURL.
+// This is what actually implements the "bsdiff apply failed mid-stream"
+// fallback described in Config.FullBinaryURL's doc comment: checkURL only
+// ever fetches a patch, so a patch that fails to apply (e.g. because the
+// on-disk binary was modified out from under it) can only be detected
+// here, at apply time.
+func (a *AutoUpdate) applyWithFallback(patch Patcher) (version string, err error) {
+	applyErr := patch.Apply()
+	if applyErr == nil {
+		return patch.Version(), nil
+	}
+
+	if a.FullBinaryURL == "" {
+		return "", applyErr
+	}
+
+	log.Errorf("autoupdate: bsdiff patch failed, falling back to full binary: %q\n", applyErr)
+
+	res, fallbackErr := checkFullBinary(a.Config, patch.Version())
+	if fallbackErr != nil {
+		return "", fmt.Errorf("autoupdate: bsdiff apply failed (%v) and full-binary fallback also failed: %v", applyErr, fallbackErr)
+	}
+
+	fallback := &Patch{res: res, v: res.Version}
+	if fallbackErr = fallback.Apply(); fallbackErr != nil {
+		return "", fmt.Errorf("autoupdate: bsdiff apply failed (%v) and full-binary fallback also failed: %v", applyErr, fallbackErr)
+	}
+
+	a.emit(a.Events, Event{Type: FullBinaryFallback, Version: fallback.Version()})
+	return fallback.Version(), nil
+}
+
+// updated records that a patch was applied successfully, notifying
+// UpdatedTo and updating a.v. The send to UpdatedTo blocks until it is read
+// or ctx is done; under Watch, whose context is never canceled, it waits for
+// a reader of UpdatedTo.
+func (a *AutoUpdate) updated(version string, ctx context.Context) {
+	select {
+	case a.UpdatedTo <- version:
+	case <-ctx.Done():
+	}
+	a.SetVersion(version)
+}
+
+func (a *AutoUpdate) loop(ctx context.Context) {
 	log.Debug("Starting to watch for updates")
+
+	scheduler := a.Scheduler
+	if scheduler == nil {
+		scheduler = NewFixedIntervalScheduler(sleepTime)
+	}
+
+	ok := true
 	for {
+		a.emit(a.Events, Event{Type: CheckStarted, Version: a.Version()})
 		patch, err := a.Query()
 
 		if err == nil {
+			ok = true
 			log.Debugf("Old: %v  New: %v", a.Version(), patch.Version())
 			if VersionCompare(a.Version(), patch.Version()) == Higher {
 				log.Debugf("autoupdate: Attempting to update to %s.", patch.Version())
+				a.emit(a.Events, Event{Type: UpdateAvailable, Version: patch.Version()})
 
-				err = patch.Apply()
+				appliedVersion, applyErr := a.applyWithFallback(patch)
 
-				if err == nil {
+				if applyErr == nil {
 					log.Debugf("autoupdate: Patching succeeded!")
-					// Updating version.
-					a.UpdatedTo <- patch.Version()
-					a.SetVersion(patch.Version())
+					a.emit(a.Events, Event{Type: PatchApplied, Version: appliedVersion})
+					a.updated(appliedVersion, ctx)
 				} else {
-					log.Errorf("autoupdate: Patching failed: %q\n", err)
+					log.Errorf("autoupdate: Patching failed: %q\n", applyErr)
+					a.emit(a.Events, Event{Type: PatchFailed, Version: patch.Version(), Err: applyErr})
 				}
 
 			} else {
 				log.Debug("autoupdate: Already up to date.")
 			}
+		} else if tooNew, isTooNew := err.(*TooNewError); isTooNew {
+			ok = true
+			log.Debugf("autoupdate: %v", tooNew)
+			a.emit(a.Events, Event{Type: PolicyBlocked, Version: tooNew.Remote, Err: tooNew})
 		} else {
+			ok = false
 			log.Debugf("autoupdate: Could not reach update server: %q\n", err)
+			a.emit(a.Events, Event{Type: CheckFailed, Err: err})
 		}
 
-		time.Sleep(sleepTime)
+		select {
+		case <-ctx.Done():
+			log.Debug("autoupdate: Stopping watch loop.")
+			return
+		case <-time.After(scheduler.Next(ok)):
+		}
 	}
 }
 
-// Watch spawns a goroutine that will apply updates whenever they're available.
+// Watch spawns a goroutine that will apply updates whenever they're
+// available. The goroutine runs until the process exits; use WatchContext
+// if you need to stop it.
 func (a *AutoUpdate) Watch() {
 	if a.v == "" {
 		// Panicking because Watch is useless without the ability to compare
 		// versions.
 		panic(`autoupdate: You must set the executable version in order to watch for updates!`)
 	}
-	go a.loop()
+	go a.loop(context.Background())
+}
+
+// WatchContext spawns a goroutine that will apply updates whenever they're
+// available, stopping cleanly when ctx is canceled. Unlike Watch, it never
+// leaks the goroutine.
+func (a *AutoUpdate) WatchContext(ctx context.Context) error {
+	if a.v == "" {
+		return errVersionNotSet
+	}
+	go a.loop(ctx)
+	return nil
 }
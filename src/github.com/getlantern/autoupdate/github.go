@@ -0,0 +1,386 @@
+package autoupdate
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/getlantern/go-update/check"
+)
+
+const githubAPIBase = "https://api.github.com"
+
+// GitHubConfig configures a GitHubReleasesSource.
+type GitHubConfig struct {
+	// Owner and Repo identify the GitHub repository whose releases are
+	// polled, e.g. "getlantern" and "lantern".
+	Owner, Repo string
+
+	// Binary is the name of the executable being updated. Assets are
+	// expected to be named "{Binary}_{os}_{arch}.zip" (or .tgz/.tar.gz).
+	Binary string
+
+	// PublicKey is the RSA/PEM key used to verify the SHA256SUMS manifest's
+	// detached signature, matching the scheme go-update's
+	// VerifySignatureWithPEM already uses for patch bodies.
+	PublicKey []byte
+
+	// FilterPatterns, if non-empty, restricts candidate releases to those
+	// whose name matches at least one of these regular expressions.
+	FilterPatterns []string
+
+	// AllowDraft and AllowPrerelease opt into releases that are normally
+	// skipped.
+	AllowDraft      bool
+	AllowPrerelease bool
+}
+
+// GitHubReleasesSource is a Source that discovers updates by querying the
+// GitHub Releases API instead of a custom check-URL.
+type GitHubReleasesSource struct {
+	cfg GitHubConfig
+}
+
+// NewGitHubSource creates a Source backed by GitHub Releases.
+func NewGitHubSource(cfg GitHubConfig) Source {
+	return &GitHubReleasesSource{cfg: cfg}
+}
+
+type githubRelease struct {
+	TagName    string        `json:"tag_name"`
+	Name       string        `json:"name"`
+	Draft      bool          `json:"draft"`
+	Prerelease bool          `json:"prerelease"`
+	Assets     []githubAsset `json:"assets"`
+}
+
+type githubAsset struct {
+	Name               string `json:"name"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+}
+
+// Check implements Source.
+func (s *GitHubReleasesSource) Check(appVersion string) (*check.Result, error) {
+	releases, err := s.listReleases()
+	if err != nil {
+		return nil, err
+	}
+
+	rel, asset, err := s.selectRelease(releases)
+	if err != nil {
+		return nil, err
+	}
+	if rel == nil {
+		// No eligible release found; treat like no update available.
+		return nil, nil
+	}
+
+	version := normalizeTag(rel.TagName)
+	if !isVersionTag(version) {
+		return nil, fmt.Errorf("autoupdate: release tag %q is not a valid version", rel.TagName)
+	}
+
+	if VersionCompare(appVersion, version) != Higher {
+		return nil, nil
+	}
+
+	sums, err := s.fetchChecksums(rel)
+	if err != nil {
+		return nil, err
+	}
+	want, ok := sums[asset.Name]
+	if !ok {
+		return nil, fmt.Errorf("autoupdate: SHA256SUMS has no entry for %q", asset.Name)
+	}
+
+	body, err := httpGet(asset.BrowserDownloadURL)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	data, sum, err := readAndSum(body)
+	if err != nil {
+		return nil, err
+	}
+	if sum != want {
+		return nil, fmt.Errorf("autoupdate: checksum mismatch for %q: got %s, want %s", asset.Name, sum, want)
+	}
+
+	bin, err := extractBinary(asset.Name, data, s.cfg.Binary)
+	if err != nil {
+		return nil, err
+	}
+
+	return &check.Result{
+		Version: version,
+		Patch:   bin,
+	}, nil
+}
+
+// listReleases fetches the releases for cfg.Owner/cfg.Repo, newest first,
+// exactly as the GitHub API returns them. It makes a single request and does
+// not follow pagination, so only the API's first page is seen.
+func (s *GitHubReleasesSource) listReleases() ([]githubRelease, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/releases", githubAPIBase, s.cfg.Owner, s.cfg.Repo)
+
+	body, err := httpGet(url)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var releases []githubRelease
+	if err := json.NewDecoder(body).Decode(&releases); err != nil {
+		return nil, fmt.Errorf("autoupdate: decoding releases: %w", err)
+	}
+	return releases, nil
+}
+
+// selectRelease picks the newest release eligible under cfg (not a draft or
+// prerelease unless opted in, matching at least one filter pattern if any
+// are set) that carries an asset for this binary/os/arch.
+func (s *GitHubReleasesSource) selectRelease(releases []githubRelease) (*githubRelease, *githubAsset, error) {
+	patterns := make([]*regexp.Regexp, 0, len(s.cfg.FilterPatterns))
+	for _, p := range s.cfg.FilterPatterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, nil, fmt.Errorf("autoupdate: invalid filter pattern %q: %w", p, err)
+		}
+		patterns = append(patterns, re)
+	}
+
+	for i := range releases {
+		rel := &releases[i]
+
+		if rel.Draft && !s.cfg.AllowDraft {
+			continue
+		}
+		if rel.Prerelease && !s.cfg.AllowPrerelease {
+			continue
+		}
+		if len(patterns) > 0 && !matchesAny(patterns, rel.Name) {
+			continue
+		}
+
+		if asset := findAsset(rel, s.cfg.Binary); asset != nil {
+			return rel, asset, nil
+		}
+	}
+
+	return nil, nil, nil
+}
+
+// findAsset returns the release asset matching "{binary}_{os}_{arch}" with
+// a .zip, .tgz, or .tar.gz suffix, or nil if there isn't one.
+func findAsset(rel *githubRelease, binary string) *githubAsset {
+	prefix := fmt.Sprintf("%s_%s_%s", binary, runtime.GOOS, runtime.GOARCH)
+	suffixes := []string{".zip", ".tgz", ".tar.gz"}
+
+	for i := range rel.Assets {
+		name := rel.Assets[i].Name
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		for _, suf := range suffixes {
+			if strings.HasSuffix(name, suf) {
+				return &rel.Assets[i]
+			}
+		}
+	}
+	return nil
+}
+
+// extractBinary pulls the named binary out of archive, a .zip, .tgz, or
+// .tar.gz as identified by archiveName's suffix. go-update expects a bare
+// executable, not the compressed release asset findAsset matched.
+func extractBinary(archiveName string, archive []byte, binary string) ([]byte, error) {
+	switch {
+	case strings.HasSuffix(archiveName, ".zip"):
+		return extractFromZip(archive, binary)
+	case strings.HasSuffix(archiveName, ".tgz"), strings.HasSuffix(archiveName, ".tar.gz"):
+		return extractFromTarGz(archive, binary)
+	default:
+		return nil, fmt.Errorf("autoupdate: %q is not a recognized archive format", archiveName)
+	}
+}
+
+func extractFromZip(archive []byte, binary string) ([]byte, error) {
+	r, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, fmt.Errorf("autoupdate: reading zip archive: %w", err)
+	}
+	for _, f := range r.File {
+		if filepath.Base(f.Name) != binary {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+	return nil, fmt.Errorf("autoupdate: zip archive has no entry named %q", binary)
+}
+
+func extractFromTarGz(archive []byte, binary string) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("autoupdate: reading gzip archive: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("autoupdate: reading tar archive: %w", err)
+		}
+		if filepath.Base(hdr.Name) != binary {
+			continue
+		}
+		return io.ReadAll(tr)
+	}
+	return nil, fmt.Errorf("autoupdate: tar archive has no entry named %q", binary)
+}
+
+// fetchChecksums downloads and parses the release's SHA256SUMS asset into a
+// map of asset name to hex-encoded digest.
+func (s *GitHubReleasesSource) fetchChecksums(rel *githubRelease) (map[string]string, error) {
+	var sumsAsset *githubAsset
+	for i := range rel.Assets {
+		if rel.Assets[i].Name == "SHA256SUMS" {
+			sumsAsset = &rel.Assets[i]
+			break
+		}
+	}
+	if sumsAsset == nil {
+		return nil, fmt.Errorf("autoupdate: release %q has no SHA256SUMS asset", rel.TagName)
+	}
+
+	// A detached signature, if present, lets callers verify SHA256SUMS
+	// itself came from cfg.PublicKey before trusting any digest in it.
+	var sigAsset *githubAsset
+	for i := range rel.Assets {
+		if rel.Assets[i].Name == "SHA256SUMS.sig" {
+			sigAsset = &rel.Assets[i]
+			break
+		}
+	}
+
+	body, err := httpGet(sumsAsset.BrowserDownloadURL)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	raw, _, err := readAndSum(body)
+	if err != nil {
+		return nil, err
+	}
+
+	if sigAsset != nil && len(s.cfg.PublicKey) > 0 {
+		sig, err := httpGet(sigAsset.BrowserDownloadURL)
+		if err != nil {
+			return nil, err
+		}
+		sigData, _, err := readAndSum(sig)
+		sig.Close()
+		if err != nil {
+			return nil, err
+		}
+		if err := verifyPEMSignature(s.cfg.PublicKey, raw, sigData); err != nil {
+			return nil, fmt.Errorf("autoupdate: SHA256SUMS signature verification failed: %w", err)
+		}
+	}
+
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(string(raw)))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		sums[fields[1]] = fields[0]
+	}
+	return sums, scanner.Err()
+}
+
+func matchesAny(patterns []*regexp.Regexp, name string) bool {
+	for _, re := range patterns {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeTag turns "vX.Y.Z" and "X.Y.Z" tag names into the "vX.Y.Z" form
+// the rest of the package expects.
+func normalizeTag(tag string) string {
+	if strings.HasPrefix(tag, "v") {
+		return tag
+	}
+	return "v" + tag
+}
+
+func httpGet(url string) (io.ReadCloser, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("autoupdate: GET %s: unexpected status %s", url, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// verifyPEMSignature checks sig against data using the RSA public key
+// encoded in pemBytes, matching the scheme go-update's
+// VerifySignatureWithPEM already uses for patch bodies.
+func verifyPEMSignature(pemBytes, data, sig []byte) error {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return fmt.Errorf("autoupdate: invalid PEM public key")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return err
+	}
+	rsaPub, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("autoupdate: public key is not RSA")
+	}
+	sum := sha256.Sum256(data)
+	return rsa.VerifyPKCS1v15(rsaPub, crypto.SHA256, sum[:], sig)
+}
+
+func readAndSum(r io.Reader) ([]byte, string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(data)
+	return data, hex.EncodeToString(sum[:]), nil
+}
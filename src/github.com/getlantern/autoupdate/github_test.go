@@ -0,0 +1,322 @@
+package autoupdate
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	cases := []struct{ in, want string }{
+		{"v1.2.3", "v1.2.3"},
+		{"1.2.3", "v1.2.3"},
+	}
+	for _, c := range cases {
+		if got := normalizeTag(c.in); got != c.want {
+			t.Errorf("normalizeTag(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestFindAsset(t *testing.T) {
+	prefix := fmt.Sprintf("app_%s_%s", runtime.GOOS, runtime.GOARCH)
+	rel := &githubRelease{
+		Assets: []githubAsset{
+			{Name: "SHA256SUMS"},
+			{Name: prefix + ".zip"},
+			{Name: "app_other_other.zip"},
+		},
+	}
+
+	asset := findAsset(rel, "app")
+	if asset == nil || asset.Name != prefix+".zip" {
+		t.Fatalf("findAsset = %+v, want %s.zip", asset, prefix)
+	}
+
+	if a := findAsset(rel, "missing"); a != nil {
+		t.Fatalf("findAsset(missing) = %+v, want nil", a)
+	}
+}
+
+func TestSelectRelease(t *testing.T) {
+	prefix := fmt.Sprintf("app_%s_%s", runtime.GOOS, runtime.GOARCH)
+	withAsset := func(name string, draft, prerelease bool) githubRelease {
+		return githubRelease{
+			Name:       name,
+			TagName:    name,
+			Draft:      draft,
+			Prerelease: prerelease,
+			Assets:     []githubAsset{{Name: prefix + ".zip"}},
+		}
+	}
+
+	t.Run("skips drafts and prereleases by default", func(t *testing.T) {
+		s := &GitHubReleasesSource{cfg: GitHubConfig{Binary: "app"}}
+		releases := []githubRelease{
+			withAsset("v2.0.0-draft", true, false),
+			withAsset("v1.5.0-rc1", false, true),
+			withAsset("v1.0.0", false, false),
+		}
+		rel, asset, err := s.selectRelease(releases)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rel == nil || asset == nil || rel.TagName != "v1.0.0" {
+			t.Fatalf("selectRelease = %+v, want v1.0.0", rel)
+		}
+	})
+
+	t.Run("opts into drafts and prereleases", func(t *testing.T) {
+		s := &GitHubReleasesSource{cfg: GitHubConfig{Binary: "app", AllowDraft: true}}
+		releases := []githubRelease{withAsset("v2.0.0-draft", true, false)}
+		rel, _, err := s.selectRelease(releases)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rel == nil || rel.TagName != "v2.0.0-draft" {
+			t.Fatalf("selectRelease = %+v, want v2.0.0-draft", rel)
+		}
+
+		s = &GitHubReleasesSource{cfg: GitHubConfig{Binary: "app", AllowPrerelease: true}}
+		releases = []githubRelease{withAsset("v1.5.0-rc1", false, true)}
+		rel, _, err = s.selectRelease(releases)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rel == nil || rel.TagName != "v1.5.0-rc1" {
+			t.Fatalf("selectRelease = %+v, want v1.5.0-rc1", rel)
+		}
+	})
+
+	t.Run("filters by pattern", func(t *testing.T) {
+		s := &GitHubReleasesSource{cfg: GitHubConfig{Binary: "app", FilterPatterns: []string{`^stable-`}}}
+		releases := []githubRelease{
+			withAsset("beta-v1.0.0", false, false),
+			withAsset("stable-v1.0.0", false, false),
+		}
+		rel, _, err := s.selectRelease(releases)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rel == nil || rel.TagName != "stable-v1.0.0" {
+			t.Fatalf("selectRelease = %+v, want stable-v1.0.0", rel)
+		}
+	})
+
+	t.Run("no eligible release returns nil, nil, nil", func(t *testing.T) {
+		s := &GitHubReleasesSource{cfg: GitHubConfig{Binary: "app"}}
+		rel, asset, err := s.selectRelease(nil)
+		if err != nil || rel != nil || asset != nil {
+			t.Fatalf("selectRelease(nil) = %+v, %+v, %v, want nil, nil, nil", rel, asset, err)
+		}
+	})
+
+	t.Run("invalid filter pattern errors", func(t *testing.T) {
+		s := &GitHubReleasesSource{cfg: GitHubConfig{Binary: "app", FilterPatterns: []string{"("}}}
+		if _, _, err := s.selectRelease(nil); err == nil {
+			t.Fatal("expected an error for an invalid filter pattern")
+		}
+	})
+}
+
+func TestFetchChecksums(t *testing.T) {
+	const sums = "deadbeef  app_linux_amd64.zip\nc0ffee  SHA256SUMS-unrelated\n"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/SHA256SUMS", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(sums))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	s := &GitHubReleasesSource{cfg: GitHubConfig{Binary: "app"}}
+	rel := &githubRelease{
+		TagName: "v1.0.0",
+		Assets: []githubAsset{
+			{Name: "SHA256SUMS", BrowserDownloadURL: srv.URL + "/SHA256SUMS"},
+		},
+	}
+
+	got, err := s.fetchChecksums(rel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["app_linux_amd64.zip"] != "deadbeef" {
+		t.Fatalf("fetchChecksums = %+v, want app_linux_amd64.zip -> deadbeef", got)
+	}
+}
+
+func TestFetchChecksumsMissingAsset(t *testing.T) {
+	s := &GitHubReleasesSource{cfg: GitHubConfig{Binary: "app"}}
+	if _, err := s.fetchChecksums(&githubRelease{TagName: "v1.0.0"}); err == nil {
+		t.Fatal("expected an error when the release has no SHA256SUMS asset")
+	}
+}
+
+func TestFetchChecksumsVerifiesSignature(t *testing.T) {
+	const sums = "deadbeef  app_linux_amd64.zip\n"
+
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := signSHA256(priv, []byte(sums))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pubBytes, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubBytes})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/SHA256SUMS", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(sums))
+	})
+	mux.HandleFunc("/SHA256SUMS.sig", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(sig)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	s := &GitHubReleasesSource{cfg: GitHubConfig{Binary: "app", PublicKey: pemBytes}}
+	rel := &githubRelease{
+		TagName: "v1.0.0",
+		Assets: []githubAsset{
+			{Name: "SHA256SUMS", BrowserDownloadURL: srv.URL + "/SHA256SUMS"},
+			{Name: "SHA256SUMS.sig", BrowserDownloadURL: srv.URL + "/SHA256SUMS.sig"},
+		},
+	}
+
+	if _, err := s.fetchChecksums(rel); err != nil {
+		t.Fatalf("fetchChecksums with a valid signature: %v", err)
+	}
+
+	mux.HandleFunc("/tampered", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(sums + "extra\n"))
+	})
+	rel.Assets[0].BrowserDownloadURL = srv.URL + "/tampered"
+	if _, err := s.fetchChecksums(rel); err == nil {
+		t.Fatal("expected fetchChecksums to reject a tampered SHA256SUMS against its signature")
+	}
+}
+
+func signSHA256(priv *rsa.PrivateKey, data []byte) ([]byte, error) {
+	sum := sha256.Sum256(data)
+	return rsa.SignPKCS1v15(rand.Reader, priv, crypto.SHA256, sum[:])
+}
+
+func TestVerifyPEMSignature(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubBytes, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubBytes})
+
+	data := []byte("hello world")
+	sig, err := signSHA256(priv, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := verifyPEMSignature(pemBytes, data, sig); err != nil {
+		t.Fatalf("verifyPEMSignature with a valid signature: %v", err)
+	}
+
+	tampered := append([]byte(nil), sig...)
+	tampered[0] ^= 0xFF
+	if err := verifyPEMSignature(pemBytes, data, tampered); err == nil {
+		t.Fatal("expected verifyPEMSignature to reject a tampered signature")
+	}
+
+	if err := verifyPEMSignature([]byte("not pem"), data, sig); err == nil {
+		t.Fatal("expected verifyPEMSignature to reject invalid PEM")
+	}
+}
+
+func TestExtractBinaryFromZip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	f, err := zw.Create("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("binary-contents"))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := extractBinary("app_linux_amd64.zip", buf.Bytes(), "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "binary-contents" {
+		t.Fatalf("extractBinary = %q, want %q", got, "binary-contents")
+	}
+
+	if _, err := extractBinary("app_linux_amd64.zip", buf.Bytes(), "missing"); err == nil {
+		t.Fatal("expected an error when the archive has no matching entry")
+	}
+}
+
+func TestExtractBinaryFromTarGz(t *testing.T) {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	contents := []byte("binary-contents")
+	if err := tw.WriteHeader(&tar.Header{Name: "app", Size: int64(len(contents)), Mode: 0755}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write(contents); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := extractBinary("app_linux_amd64.tar.gz", buf.Bytes(), "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "binary-contents" {
+		t.Fatalf("extractBinary = %q, want %q", got, "binary-contents")
+	}
+}
+
+func TestExtractBinaryUnrecognizedFormat(t *testing.T) {
+	if _, err := extractBinary("app.rar", nil, "app"); err == nil {
+		t.Fatal("expected an error for an unrecognized archive suffix")
+	}
+}
+
+func TestHttpGetNonOKStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	if _, err := httpGet(srv.URL); err == nil {
+		t.Fatal("expected an error for a non-200 response")
+	}
+}
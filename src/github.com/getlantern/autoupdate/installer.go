@@ -0,0 +1,248 @@
+package autoupdate
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// pendingMarker is the name, within Installer.BaseDir, of the file that
+// marks a staged version as pending a health check. Stage writes it with the
+// directory that was current before the swap; ConfirmHealthy and Rollback
+// remove it.
+const pendingMarker = "pending"
+
+// HealthCheck is run by ConfirmHealthy shortly after a staged version
+// starts, with a context that expires after ConfirmHealthy's timeout.
+// Returning an error makes ConfirmHealthy roll back to the previous version
+// right away.
+type HealthCheck func(ctx context.Context) error
+
+// Installer stages new versions into sibling directories under BaseDir and
+// swaps a "current" symlink to adopt them, so a botched patch never leaves
+// the running executable half-overwritten. This mirrors the staged-install
+// pattern used by Teleport's client autoupdater.
+type Installer struct {
+	// BaseDir holds one directory per installed version ("versions/vX.Y.Z")
+	// plus the "current" symlink (or, on Windows, launcher stub) and the
+	// pending marker.
+	BaseDir string
+	// Binary is the name of the executable inside each version directory.
+	Binary string
+	// KeepVersions is how many past versions to retain for rollback and GC.
+	// Defaults to 3 if zero.
+	KeepVersions int
+}
+
+func (i *Installer) keepVersions() int {
+	if i.KeepVersions <= 0 {
+		return 3
+	}
+	return i.KeepVersions
+}
+
+func (i *Installer) versionDir(version string) string {
+	return filepath.Join(i.BaseDir, "versions", version)
+}
+
+func (i *Installer) currentPath() string {
+	return filepath.Join(i.BaseDir, "current")
+}
+
+func (i *Installer) pendingMarkerPath() string {
+	return filepath.Join(i.BaseDir, pendingMarker)
+}
+
+// Stage writes data (the verified binary for version) into its own
+// versions/vX.Y.Z directory and atomically swaps "current" to point at it.
+// It also writes the pending marker, which stays until ConfirmHealthy or
+// Rollback removes it. A failed health check in ConfirmHealthy rolls back; a
+// crash before ConfirmHealthy runs rolls nothing back until Rollback is
+// called.
+func (i *Installer) Stage(version string, data []byte) error {
+	dir := i.versionDir(version)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	binPath := filepath.Join(dir, i.Binary)
+	if err := ioutil.WriteFile(binPath, data, 0755); err != nil {
+		return err
+	}
+
+	// currentDir must be read before swapCurrent overwrites it. Its error is
+	// expected (and ignored) the first time Stage ever runs, when there's no
+	// previous version yet.
+	previous, _ := i.currentDir()
+
+	if err := i.swapCurrent(dir); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(i.pendingMarkerPath(), []byte(previous), 0644); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// swapCurrent atomically points "current" at dir. On POSIX this is a
+// symlink rename; on Windows, where symlinks to executables are awkward to
+// exec in place, it rewrites a small launcher stub that execs the target
+// binary instead.
+func (i *Installer) swapCurrent(dir string) error {
+	if runtime.GOOS == "windows" {
+		return writeLauncherStub(i.currentPath()+".cmd", dir, filepath.Join(dir, i.Binary))
+	}
+
+	tmp := i.currentPath() + ".tmp"
+	os.Remove(tmp)
+	if err := os.Symlink(dir, tmp); err != nil {
+		return err
+	}
+	return os.Rename(tmp, i.currentPath())
+}
+
+// currentDir returns the version directory "current" points at, on whatever
+// platform-specific form swapCurrent used to record it.
+func (i *Installer) currentDir() (string, error) {
+	if runtime.GOOS == "windows" {
+		data, err := ioutil.ReadFile(i.currentPath() + ".cmd")
+		if err != nil {
+			return "", err
+		}
+		return parseLauncherStub(string(data))
+	}
+	return os.Readlink(i.currentPath())
+}
+
+// launcherStubDirPrefix tags the line in the launcher stub that records
+// which version directory it execs into, so currentDir can read it back
+// without having to parse the exec line's quoting.
+const launcherStubDirPrefix = "REM current-dir="
+
+// writeLauncherStub writes a minimal batch script that execs target,
+// standing in for a symlink on platforms where those don't behave like one.
+// It also records dir in a comment line so currentDir can recover it later.
+func writeLauncherStub(stubPath, dir, target string) error {
+	contents := fmt.Sprintf("@echo off\r\n%s%s\r\n\"%s\" %%*\r\n", launcherStubDirPrefix, dir, target)
+	return ioutil.WriteFile(stubPath, []byte(contents), 0755)
+}
+
+// parseLauncherStub extracts the version directory recorded by
+// writeLauncherStub.
+func parseLauncherStub(contents string) (string, error) {
+	for _, line := range strings.Split(contents, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if dir, ok := strings.CutPrefix(line, launcherStubDirPrefix); ok {
+			return dir, nil
+		}
+	}
+	return "", fmt.Errorf("autoupdate: launcher stub has no recorded current directory")
+}
+
+// ConfirmHealthy should be called once, early in the new version's startup,
+// after check returns without error within timeout. On success it clears
+// the pending marker, adopting the staged version permanently and running
+// GC. On failure (or timeout) it rolls back to the previous version.
+func (i *Installer) ConfirmHealthy(ctx context.Context, timeout time.Duration, check HealthCheck) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if err := check(ctx); err != nil {
+		log.Errorf("autoupdate: health check failed, rolling back: %q\n", err)
+		return i.Rollback()
+	}
+
+	if err := os.Remove(i.pendingMarkerPath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return i.gc()
+}
+
+// NeedsRollback reports whether the previous launch staged a version but
+// never confirmed it healthy, meaning it likely crashed before calling
+// ConfirmHealthy. Callers should check this before even starting up the
+// staged version's normal logic.
+func (i *Installer) NeedsRollback() bool {
+	_, err := os.Stat(i.pendingMarkerPath())
+	return err == nil
+}
+
+// Rollback reverts "current" to the version recorded in the pending
+// marker (the one active before the most recent Stage), for manual
+// recovery or automatic use by ConfirmHealthy/NeedsRollback callers.
+func (i *Installer) Rollback() error {
+	previous, err := ioutil.ReadFile(i.pendingMarkerPath())
+	if err != nil {
+		return fmt.Errorf("autoupdate: no staged version to roll back from: %w", err)
+	}
+	if len(previous) == 0 {
+		return fmt.Errorf("autoupdate: no previous version recorded to roll back to")
+	}
+
+	if err := i.swapCurrent(string(previous)); err != nil {
+		return err
+	}
+	return os.Remove(i.pendingMarkerPath())
+}
+
+// gc removes all but the KeepVersions most recent version directories.
+func (i *Installer) gc() error {
+	entries, err := ioutil.ReadDir(filepath.Join(i.BaseDir, "versions"))
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Slice(names, func(a, b int) bool {
+		return VersionCompare(names[b], names[a]) == Higher
+	})
+
+	for _, name := range names[min(len(names), i.keepVersions()):] {
+		if err := os.RemoveAll(filepath.Join(i.BaseDir, "versions", name)); err != nil {
+			log.Errorf("autoupdate: could not GC old version %s: %q\n", name, err)
+		}
+	}
+	return nil
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// installer is the process-wide Installer configured via SetInstaller, used
+// by the package-level Rollback helper.
+var installer *Installer
+
+// SetInstaller configures the process-wide Installer used by the
+// package-level Rollback. Watch and WatchContext do not use it: updates they
+// find are applied in place by go-update.
+func SetInstaller(i *Installer) {
+	installer = i
+}
+
+// Rollback reverts to the previously installed version using the
+// process-wide Installer configured via SetInstaller. It's meant for manual
+// recovery, e.g. from a support tool or a "downgrade" menu item.
+func Rollback() error {
+	if installer == nil {
+		return fmt.Errorf("autoupdate: no Installer configured, call SetInstaller first")
+	}
+	return installer.Rollback()
+}
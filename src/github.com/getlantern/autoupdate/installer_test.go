@@ -0,0 +1,132 @@
+package autoupdate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestInstallerStageAndConfirmHealthy(t *testing.T) {
+	i := &Installer{BaseDir: t.TempDir(), Binary: "app"}
+
+	if err := i.Stage("v1.0.0", []byte("binary-v1")); err != nil {
+		t.Fatalf("Stage: %v", err)
+	}
+	if !i.NeedsRollback() {
+		t.Fatal("expected NeedsRollback after Stage with no ConfirmHealthy yet")
+	}
+
+	ok := func(ctx context.Context) error { return nil }
+	if err := i.ConfirmHealthy(context.Background(), time.Second, ok); err != nil {
+		t.Fatalf("ConfirmHealthy: %v", err)
+	}
+	if i.NeedsRollback() {
+		t.Fatal("expected NeedsRollback to clear after a successful ConfirmHealthy")
+	}
+
+	dir, err := i.currentDir()
+	if err != nil {
+		t.Fatalf("currentDir: %v", err)
+	}
+	if dir != i.versionDir("v1.0.0") {
+		t.Fatalf("currentDir = %q, want %q", dir, i.versionDir("v1.0.0"))
+	}
+}
+
+func TestInstallerConfirmHealthyRollsBackOnFailure(t *testing.T) {
+	i := &Installer{BaseDir: t.TempDir(), Binary: "app"}
+
+	if err := i.Stage("v1.0.0", []byte("binary-v1")); err != nil {
+		t.Fatalf("Stage v1.0.0: %v", err)
+	}
+	if err := i.ConfirmHealthy(context.Background(), time.Second, func(ctx context.Context) error { return nil }); err != nil {
+		t.Fatalf("ConfirmHealthy v1.0.0: %v", err)
+	}
+
+	if err := i.Stage("v2.0.0", []byte("binary-v2")); err != nil {
+		t.Fatalf("Stage v2.0.0: %v", err)
+	}
+
+	failing := func(ctx context.Context) error { return context.DeadlineExceeded }
+	if err := i.ConfirmHealthy(context.Background(), time.Second, failing); err != nil {
+		t.Fatalf("ConfirmHealthy (rollback path): %v", err)
+	}
+
+	dir, err := i.currentDir()
+	if err != nil {
+		t.Fatalf("currentDir after rollback: %v", err)
+	}
+	if dir != i.versionDir("v1.0.0") {
+		t.Fatalf("currentDir after rollback = %q, want %q (v1.0.0)", dir, i.versionDir("v1.0.0"))
+	}
+}
+
+func TestInstallerRollbackWithNoPendingVersion(t *testing.T) {
+	i := &Installer{BaseDir: t.TempDir(), Binary: "app"}
+
+	if err := i.Rollback(); err == nil {
+		t.Fatal("expected an error rolling back with no staged version")
+	}
+}
+
+func TestInstallerGCKeepsNewestVersions(t *testing.T) {
+	i := &Installer{BaseDir: t.TempDir(), Binary: "app", KeepVersions: 2}
+
+	for _, v := range []string{"v1.0.0", "v1.1.0", "v1.2.0"} {
+		if err := os.MkdirAll(i.versionDir(v), 0755); err != nil {
+			t.Fatalf("MkdirAll %s: %v", v, err)
+		}
+	}
+
+	if err := i.gc(); err != nil {
+		t.Fatalf("gc: %v", err)
+	}
+
+	if _, err := os.Stat(i.versionDir("v1.0.0")); !os.IsNotExist(err) {
+		t.Fatalf("expected oldest version v1.0.0 to be GC'd, stat err = %v", err)
+	}
+	for _, v := range []string{"v1.1.0", "v1.2.0"} {
+		if _, err := os.Stat(i.versionDir(v)); err != nil {
+			t.Fatalf("expected %s to survive GC, stat err = %v", v, err)
+		}
+	}
+}
+
+func TestInstallerKeepVersionsDefaultsToThree(t *testing.T) {
+	i := &Installer{BaseDir: t.TempDir(), Binary: "app"}
+	if got := i.keepVersions(); got != 3 {
+		t.Fatalf("keepVersions() = %v, want default 3", got)
+	}
+}
+
+func TestWriteAndParseLauncherStub(t *testing.T) {
+	dir := t.TempDir()
+	stubPath := filepath.Join(dir, "current.cmd")
+	versionDir := filepath.Join(dir, "versions", "v1.2.3")
+	target := filepath.Join(versionDir, "app.exe")
+
+	if err := writeLauncherStub(stubPath, versionDir, target); err != nil {
+		t.Fatalf("writeLauncherStub: %v", err)
+	}
+
+	data, err := os.ReadFile(stubPath)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+
+	got, err := parseLauncherStub(string(data))
+	if err != nil {
+		t.Fatalf("parseLauncherStub: %v", err)
+	}
+	if got != versionDir {
+		t.Fatalf("parseLauncherStub = %q, want %q", got, versionDir)
+	}
+}
+
+func TestParseLauncherStubRejectsMissingMarker(t *testing.T) {
+	if _, err := parseLauncherStub("@echo off\r\n\"C:\\app.exe\" %*\r\n"); err == nil {
+		t.Fatal("expected an error for a stub with no recorded current directory")
+	}
+}
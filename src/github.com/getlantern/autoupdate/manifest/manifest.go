@@ -0,0 +1,176 @@
+// Package manifest defines the signed update manifest format served at
+// Config.URL: a single JSON document listing every platform, architecture,
+// and channel a release is available for, plus enough metadata (minimum
+// upgrade-from version, per-asset checksums) for a client to pick exactly
+// one asset without the server having to pre-select on its behalf.
+//
+// Because this is now the security-critical trust root for autoupdate
+// (everything downstream assumes the manifest is genuine), Parse is meant
+// to be exercised by the fuzz tests in this package rather than trusted on
+// code review alone.
+package manifest
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+)
+
+// Asset is a single downloadable artifact for one platform/architecture.
+type Asset struct {
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256"`
+}
+
+// Release describes one version's availability on one channel.
+type Release struct {
+	Version string `json:"version"`
+	Channel string `json:"channel"`
+
+	// MinUpgradeFrom is the oldest version allowed to upgrade directly to
+	// this release; older clients must be offered an intermediate release
+	// first.
+	MinUpgradeFrom string `json:"min_upgrade_from,omitempty"`
+	ReleaseNotes   string `json:"release_notes,omitempty"`
+
+	Assets []Asset `json:"assets"`
+}
+
+// KeyRotation lets the manifest vouch for a new signing key before any
+// manifest is actually signed with it, so clients pick up the new key
+// ahead of time and a later rotation doesn't require reshipping every
+// client with a new pinned key.
+type KeyRotation struct {
+	// NewPublicKey is the raw 32-byte Ed25519 public key taking over.
+	NewPublicKey []byte `json:"new_public_key"`
+	// Signature is NewPublicKey signed by the current key, proving the
+	// current key vouches for it.
+	Signature []byte `json:"signature"`
+}
+
+// Manifest is the unsigned document describing every release.
+type Manifest struct {
+	Releases []Release `json:"releases"`
+
+	// Rotation, if present, introduces a future signing key.
+	Rotation *KeyRotation `json:"rotation,omitempty"`
+}
+
+// Signed wraps a Manifest with the Ed25519 signature over its canonical
+// JSON encoding. This is the document actually served at Config.URL.
+type Signed struct {
+	Manifest  json.RawMessage `json:"manifest"`
+	Signature []byte          `json:"signature"`
+}
+
+// Sign encodes m and signs it with priv, producing the document to serve.
+func Sign(priv ed25519.PrivateKey, m *Manifest) (*Signed, error) {
+	raw, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return &Signed{
+		Manifest:  raw,
+		Signature: ed25519.Sign(priv, raw),
+	}, nil
+}
+
+// Parse verifies a Signed document against pub and returns the enclosed
+// Manifest. If the manifest introduces a key rotation, the *new* public key
+// is also returned so the caller can start trusting it for future fetches;
+// otherwise it's pub, unchanged.
+//
+// Parse never trusts the manifest before its signature is verified: the
+// envelope is decoded only to extract the manifest bytes and signature, and
+// those bytes are checked against pub before they are unmarshaled into a
+// Manifest.
+func Parse(data []byte, pub ed25519.PublicKey) (m *Manifest, nextPub ed25519.PublicKey, err error) {
+	if len(pub) != ed25519.PublicKeySize {
+		return nil, nil, fmt.Errorf("manifest: public key must be %d bytes", ed25519.PublicKeySize)
+	}
+
+	var signed Signed
+	if err := json.Unmarshal(data, &signed); err != nil {
+		return nil, nil, fmt.Errorf("manifest: invalid envelope: %w", err)
+	}
+
+	if !ed25519.Verify(pub, signed.Manifest, signed.Signature) {
+		return nil, nil, fmt.Errorf("manifest: signature verification failed")
+	}
+
+	m = &Manifest{}
+	if err := json.Unmarshal(signed.Manifest, m); err != nil {
+		return nil, nil, fmt.Errorf("manifest: invalid manifest: %w", err)
+	}
+
+	nextPub = pub
+	if m.Rotation != nil {
+		if len(m.Rotation.NewPublicKey) != ed25519.PublicKeySize {
+			return nil, nil, fmt.Errorf("manifest: rotation key must be %d bytes", ed25519.PublicKeySize)
+		}
+		if !ed25519.Verify(pub, m.Rotation.NewPublicKey, m.Rotation.Signature) {
+			return nil, nil, fmt.Errorf("manifest: rotation signature verification failed")
+		}
+		nextPub = ed25519.PublicKey(m.Rotation.NewPublicKey)
+	}
+
+	return m, nextPub, nil
+}
+
+// Select picks the asset for (channel, os, arch) belonging to the newest
+// release on that channel that currentVersion is allowed to upgrade to
+// directly: newer than currentVersion, and with currentVersion >= that
+// release's MinUpgradeFrom. Releases on the channel that are newer than
+// currentVersion but require a higher MinUpgradeFrom than currentVersion
+// are skipped rather than causing an error, so a client behind the floor
+// is offered the newest *reachable* release instead as a stepping stone.
+// It returns nil, nil if nothing matches.
+func Select(m *Manifest, channel, osName, arch, currentVersion string, isNewer func(a, b string) bool) (*Release, *Asset, error) {
+	var best *Release
+	var bestAsset *Asset
+
+	for i := range m.Releases {
+		rel := &m.Releases[i]
+		if rel.Channel != channel {
+			continue
+		}
+		if !isNewer(rel.Version, currentVersion) {
+			continue
+		}
+		if rel.MinUpgradeFrom != "" && isNewer(rel.MinUpgradeFrom, currentVersion) {
+			continue
+		}
+
+		asset := findAsset(rel, osName, arch)
+		if asset == nil {
+			continue
+		}
+
+		if best == nil || isNewer(rel.Version, best.Version) {
+			best = rel
+			bestAsset = asset
+		}
+	}
+
+	return best, bestAsset, nil
+}
+
+func findAsset(rel *Release, osName, arch string) *Asset {
+	for i := range rel.Assets {
+		if rel.Assets[i].OS == osName && rel.Assets[i].Arch == arch {
+			return &rel.Assets[i]
+		}
+	}
+	return nil
+}
+
+// VerifyChecksum reports whether data's SHA-256 digest matches asset.SHA256
+// (lowercase hex, as produced by the sha256sum tool).
+func VerifyChecksum(asset *Asset, data []byte) bool {
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf("%x", sum) == asset.SHA256
+}
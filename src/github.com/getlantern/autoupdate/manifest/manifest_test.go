@@ -0,0 +1,200 @@
+package manifest
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"testing"
+)
+
+func genKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pub, priv
+}
+
+func TestSignAndParseRoundTrip(t *testing.T) {
+	pub, priv := genKey(t)
+
+	m := &Manifest{
+		Releases: []Release{
+			{
+				Version: "v1.2.3",
+				Channel: "stable",
+				Assets: []Asset{
+					{OS: "linux", Arch: "amd64", URL: "https://example.com/a", SHA256: "deadbeef"},
+				},
+			},
+		},
+	}
+
+	signed, err := Sign(priv, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, nextPub, err := Parse(data, pub)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(parsed.Releases) != 1 || parsed.Releases[0].Version != "v1.2.3" {
+		t.Fatalf("unexpected manifest: %+v", parsed)
+	}
+	if !nextPub.Equal(pub) {
+		t.Fatal("expected nextPub to equal pub when there's no rotation")
+	}
+}
+
+func TestParseRejectsTamperedSignature(t *testing.T) {
+	pub, priv := genKey(t)
+
+	signed, err := Sign(priv, &Manifest{Releases: []Release{{Version: "v1.0.0"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed.Signature[0] ^= 0xFF
+
+	data, err := json.Marshal(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := Parse(data, pub); err == nil {
+		t.Fatal("expected Parse to reject a tampered signature")
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldPub, oldPriv := genKey(t)
+	newPub, _ := genKey(t)
+
+	m := &Manifest{
+		Releases: []Release{{Version: "v2.0.0", Channel: "stable"}},
+		Rotation: &KeyRotation{
+			NewPublicKey: []byte(newPub),
+			Signature:    ed25519.Sign(oldPriv, []byte(newPub)),
+		},
+	}
+
+	signed, err := Sign(oldPriv, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, nextPub, err := Parse(data, oldPub)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if !nextPub.Equal(newPub) {
+		t.Fatal("expected nextPub to be the rotated key")
+	}
+}
+
+func TestSelectPicksNewestMatchingAsset(t *testing.T) {
+	m := &Manifest{
+		Releases: []Release{
+			{Version: "v1.0.0", Channel: "stable", Assets: []Asset{{OS: "linux", Arch: "amd64"}}},
+			{Version: "v1.2.0", Channel: "stable", Assets: []Asset{{OS: "linux", Arch: "amd64"}}},
+			{Version: "v1.3.0", Channel: "beta", Assets: []Asset{{OS: "linux", Arch: "amd64"}}},
+		},
+	}
+
+	isNewer := func(a, b string) bool { return a > b }
+
+	rel, asset, err := Select(m, "stable", "linux", "amd64", "v0.9.0", isNewer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rel == nil || asset == nil || rel.Version != "v1.2.0" {
+		t.Fatalf("expected v1.2.0, got %+v", rel)
+	}
+
+	rel, _, err = Select(m, "nightly", "linux", "amd64", "v0.9.0", isNewer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rel != nil {
+		t.Fatalf("expected no match for unknown channel, got %+v", rel)
+	}
+}
+
+func TestSelectFallsBackBelowMinUpgradeFromFloor(t *testing.T) {
+	m := &Manifest{
+		Releases: []Release{
+			{Version: "v1.1.0", Channel: "stable", Assets: []Asset{{OS: "linux", Arch: "amd64"}}},
+			{
+				Version:        "v2.0.0",
+				Channel:        "stable",
+				MinUpgradeFrom: "v1.5.0",
+				Assets:         []Asset{{OS: "linux", Arch: "amd64"}},
+			},
+		},
+	}
+
+	isNewer := func(a, b string) bool { return a > b }
+
+	// v1.0.0 can't jump straight to v2.0.0 (floor is v1.5.0), so it should
+	// be offered the reachable v1.1.0 stepping stone instead of an error.
+	rel, asset, err := Select(m, "stable", "linux", "amd64", "v1.0.0", isNewer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rel == nil || asset == nil || rel.Version != "v1.1.0" {
+		t.Fatalf("expected stepping-stone v1.1.0, got %+v", rel)
+	}
+
+	// v1.8.0 clears the floor, so it should go straight to v2.0.0.
+	rel, _, err = Select(m, "stable", "linux", "amd64", "v1.8.0", isNewer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rel == nil || rel.Version != "v2.0.0" {
+		t.Fatalf("expected v2.0.0, got %+v", rel)
+	}
+}
+
+// FuzzParse feeds arbitrary bytes to Parse with a fixed Ed25519 key. It must
+// never panic, regardless of how malformed the envelope or embedded
+// manifest JSON is: this is the trust root for every binary the process
+// will ever run.
+func FuzzParse(f *testing.F) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	signed, err := Sign(priv, &Manifest{Releases: []Release{{Version: "v1.0.0", Channel: "stable"}}})
+	if err != nil {
+		f.Fatal(err)
+	}
+	seed, err := json.Marshal(signed)
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	f.Add(seed)
+	f.Add([]byte(`{}`))
+	f.Add([]byte(`not json at all`))
+	f.Add([]byte(`{"manifest": "not an object", "signature": "AA=="}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		defer func() {
+			if r := recover(); r != nil {
+				t.Fatalf("Parse panicked on input %q: %v", data, r)
+			}
+		}()
+		Parse(data, pub)
+	})
+}
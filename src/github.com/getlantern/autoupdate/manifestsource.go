@@ -0,0 +1,107 @@
+package autoupdate
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"runtime"
+
+	"github.com/getlantern/go-update/check"
+
+	"github.com/getlantern/autoupdate/manifest"
+)
+
+// ManifestConfig configures a ManifestSource.
+type ManifestConfig struct {
+	// URL serves the signed manifest document.
+	URL string
+	// PublicKey is the Ed25519 public key (raw 32 bytes) the manifest must
+	// be signed with. ManifestSource updates this internally when the
+	// manifest announces a key rotation.
+	PublicKey ed25519.PublicKey
+}
+
+// ManifestSource is a Source backed by the multi-asset, multi-channel
+// manifest format in the manifest subpackage, rather than a single-artifact
+// check.Result response. It's the trust root recommended for new
+// deployments; GitHubReleasesSource and the plain URL-based Source remain
+// for simpler setups.
+type ManifestSource struct {
+	cfg ManifestConfig
+
+	channel string
+	os      string
+	arch    string
+}
+
+// NewManifestSource creates a Source backed by a signed manifest document.
+// The channel and target os/arch default to "stable" and the running
+// binary's platform; use SetChannel/SetArch on the owning AutoUpdate to
+// change them.
+func NewManifestSource(cfg ManifestConfig) *ManifestSource {
+	return &ManifestSource{cfg: cfg, channel: "stable", os: runtime.GOOS, arch: runtime.GOARCH}
+}
+
+// SetChannel switches which release channel (stable/beta/nightly/...) this
+// source looks for updates on.
+func (s *ManifestSource) SetChannel(channel string) {
+	s.channel = channel
+}
+
+// SetArch overrides the platform this source requests assets for. Useful
+// for a 32-bit process checking for a 64-bit upgrade, or similar.
+func (s *ManifestSource) SetArch(os, arch string) {
+	s.os = os
+	s.arch = arch
+}
+
+// Check implements Source.
+func (s *ManifestSource) Check(appVersion string) (*check.Result, error) {
+	body, err := httpGet(s.cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	data, _, err := readAndSum(body)
+	if err != nil {
+		return nil, err
+	}
+
+	m, nextPub, err := manifest.Parse(data, s.cfg.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("autoupdate: invalid manifest: %w", err)
+	}
+	// Adopt a rotated key immediately so the next Check verifies against
+	// it, without requiring a new client release.
+	s.cfg.PublicKey = nextPub
+
+	// Select already restricts candidates to releases newer than appVersion
+	// that appVersion is allowed to reach directly; if the newest release on
+	// this channel requires a higher floor than appVersion, Select falls
+	// back to the newest one appVersion *can* reach instead of erroring out.
+	rel, asset, err := manifest.Select(m, s.channel, s.os, s.arch, appVersion, func(a, b string) bool {
+		return VersionCompare(b, a) == Higher
+	})
+	if err != nil {
+		return nil, err
+	}
+	if rel == nil {
+		return nil, nil
+	}
+
+	assetBody, err := httpGet(asset.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer assetBody.Close()
+
+	assetData, _, err := readAndSum(assetBody)
+	if err != nil {
+		return nil, err
+	}
+	if !manifest.VerifyChecksum(asset, assetData) {
+		return nil, fmt.Errorf("autoupdate: checksum mismatch for asset %q", asset.URL)
+	}
+
+	return &check.Result{Version: rel.Version, Patch: assetData}, nil
+}
@@ -0,0 +1,95 @@
+package autoupdate
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/getlantern/go-update"
+	"github.com/getlantern/go-update/check"
+)
+
+// Patch type names advertised to the server over the wire via
+// check.Params.Tags. "none" means a full binary replacement rather than a
+// diff.
+const (
+	patchTypeBSDiff    = "bsdiff"
+	patchTypeZstdPatch = "zstd-patch"
+	patchTypeNone      = "none"
+)
+
+// defaultPatchTypes is the set of patch formats this client will advertise
+// support for, in preference order.
+var defaultPatchTypes = []string{patchTypeBSDiff, patchTypeZstdPatch, patchTypeNone}
+
+// checkURL uses go-update to look for updates via cfg.URL, backing the
+// default urlSource. It tells the server which patch formats this client
+// understands via the "patch_types" tag, but go-update's check protocol has
+// no way to report back which one (if any) the server actually chose, so
+// this always asks go-update to apply a bsdiff patch; the tag is
+// informational only for now. If that patch fails to apply, AutoUpdate.loop
+// falls back to checkFullBinary instead, which is the fallback this
+// package actually implements.
+//
+// Any failure to reach the server, or any other check-level error, is
+// returned as-is so callers (scheduling, CheckFailed events) see it; this
+// function never itself attempts the full-binary fallback.
+func checkURL(cfg *Config, appVersion string) (*check.Result, error) {
+	types := cfg.PatchTypes
+	if len(types) == 0 {
+		types = defaultPatchTypes
+	}
+
+	param := check.Params{
+		AppVersion: appVersion,
+		Tags:       map[string]string{"patch_types": strings.Join(types, ",")},
+	}
+
+	up := update.New().ApplyPatch(update.PATCHTYPE_BSDIFF)
+
+	if _, err := up.VerifySignatureWithPEM(cfg.PublicKey); err != nil {
+		return nil, err
+	}
+
+	res, err := param.CheckForUpdate(cfg.URL, up)
+	if err != nil {
+		if err == check.NoUpdateAvailable {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// checkFullBinary downloads and verifies the full signed binary for
+// version from cfg.FullBinaryURL. It's used as the bsdiff-apply fallback
+// in AutoUpdate.loop; no Source calls it.
+func checkFullBinary(cfg *Config, version string) (*check.Result, error) {
+	if cfg.FullBinaryURL == "" {
+		return nil, fmt.Errorf("autoupdate: no FullBinaryURL configured for full-binary fallback")
+	}
+
+	fullURL := fillURLTemplate(cfg.FullBinaryURL, version)
+
+	up := update.New()
+	if _, err := up.VerifySignatureWithPEM(cfg.PublicKey); err != nil {
+		return nil, err
+	}
+	if err := up.FromUrl(fullURL); err != nil {
+		return nil, err
+	}
+
+	return &check.Result{Version: version}, nil
+}
+
+// fillURLTemplate substitutes {version}, {os}, and {arch} placeholders in a
+// FullBinaryURL template.
+func fillURLTemplate(tmpl, version string) string {
+	r := strings.NewReplacer(
+		"{version}", version,
+		"{os}", runtime.GOOS,
+		"{arch}", runtime.GOARCH,
+	)
+	return r.Replace(tmpl)
+}
@@ -0,0 +1,36 @@
+package autoupdate
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestFillURLTemplate(t *testing.T) {
+	got := fillURLTemplate("https://example.com/{os}/{arch}/app-{version}.bin", "v1.2.3")
+	want := "https://example.com/" + runtime.GOOS + "/" + runtime.GOARCH + "/app-v1.2.3.bin"
+	if got != want {
+		t.Fatalf("fillURLTemplate = %q, want %q", got, want)
+	}
+}
+
+func TestFillURLTemplateNoPlaceholders(t *testing.T) {
+	got := fillURLTemplate("https://example.com/app.bin", "v1.2.3")
+	if got != "https://example.com/app.bin" {
+		t.Fatalf("fillURLTemplate = %q, want input unchanged", got)
+	}
+}
+
+func TestDefaultPatchTypesPreferenceOrder(t *testing.T) {
+	joined := strings.Join(defaultPatchTypes, ",")
+	if joined != "bsdiff,zstd-patch,none" {
+		t.Fatalf("defaultPatchTypes = %q, want bsdiff,zstd-patch,none", joined)
+	}
+}
+
+func TestCheckFullBinaryRequiresFullBinaryURL(t *testing.T) {
+	cfg := &Config{}
+	if _, err := checkFullBinary(cfg, "v1.2.3"); err == nil {
+		t.Fatal("expected an error when FullBinaryURL is unset")
+	}
+}
@@ -0,0 +1,197 @@
+package autoupdate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RolloutPolicy governs whether AutoUpdate is allowed to apply a discovered
+// patch, independent of whether one is available. It lets an embedding app
+// stage rollouts (pin a canary cohort to one version, cap how far a client
+// can jump forward) without another network round-trip: Allows only looks
+// at the two version strings it's given.
+//
+// The zero value behaves like "auto": always apply the newest version
+// found, matching AutoUpdate's behavior before RolloutPolicy existed.
+type RolloutPolicy struct {
+	kind rolloutKind
+	// version is the floor (min), the pin target (pin), or unused.
+	version string
+	// steps is the max number of minor versions a "path+N" policy may move
+	// forward in one step.
+	steps int
+}
+
+type rolloutKind int
+
+const (
+	rolloutAuto rolloutKind = iota
+	rolloutMin
+	rolloutPin
+	rolloutPath
+	rolloutOff
+)
+
+// AutoRollout always applies the newest available version. This is the
+// default when no RolloutPolicy is set.
+func AutoRollout() RolloutPolicy {
+	return RolloutPolicy{kind: rolloutAuto}
+}
+
+// MinRollout refuses to downgrade below floor but otherwise auto-upgrades,
+// mirroring GOTOOLCHAIN's "min=" directive.
+func MinRollout(floor string) RolloutPolicy {
+	return RolloutPolicy{kind: rolloutMin, version: floor}
+}
+
+// PinRollout only applies an update if the remote version is exactly
+// version, letting a canary cohort be held on a specific build.
+func PinRollout(version string) RolloutPolicy {
+	return RolloutPolicy{kind: rolloutPin, version: version}
+}
+
+// PathRollout only moves forward by at most maxMinor minor versions per
+// step, preventing a client from skipping ahead several releases at once.
+func PathRollout(maxMinor int) RolloutPolicy {
+	return RolloutPolicy{kind: rolloutPath, steps: maxMinor}
+}
+
+// OffRollout never applies an update, regardless of what Source reports.
+func OffRollout() RolloutPolicy {
+	return RolloutPolicy{kind: rolloutOff}
+}
+
+// ParseRolloutPolicy parses the "auto" / "min=vX.Y.Z" / "pin=vX.Y.Z" /
+// "path+N" / "off" string forms, so rollout tiers can be configured the same
+// way GOTOOLCHAIN is: as a single string pulled from a config file or flag.
+func ParseRolloutPolicy(s string) (RolloutPolicy, error) {
+	switch {
+	case s == "auto" || s == "":
+		return AutoRollout(), nil
+	case s == "off":
+		return OffRollout(), nil
+	case strings.HasPrefix(s, "min="):
+		return MinRollout(strings.TrimPrefix(s, "min=")), nil
+	case strings.HasPrefix(s, "pin="):
+		return PinRollout(strings.TrimPrefix(s, "pin=")), nil
+	case strings.HasPrefix(s, "path+"):
+		n, err := strconv.Atoi(strings.TrimPrefix(s, "path+"))
+		if err != nil || n < 0 {
+			return RolloutPolicy{}, fmt.Errorf("autoupdate: invalid rollout policy %q", s)
+		}
+		return PathRollout(n), nil
+	default:
+		return RolloutPolicy{}, fmt.Errorf("autoupdate: invalid rollout policy %q", s)
+	}
+}
+
+// RolloutBlockReason identifies why a RolloutPolicy rejected a remote
+// version. "Too new" (skipping ahead further than the policy allows) is
+// only one of several distinct causes, and they read very differently in
+// logs and telemetry.
+type RolloutBlockReason int
+
+const (
+	// BlockedTooNew means a "path+N" policy refused to skip ahead this far
+	// in one step.
+	BlockedTooNew RolloutBlockReason = iota
+	// BlockedTooOld means a "min=" policy refused to move to a version
+	// below its floor (i.e. this would be a downgrade).
+	BlockedTooOld
+	// BlockedNotPinned means a "pin=" policy only accepts one exact
+	// version, and remote isn't it.
+	BlockedNotPinned
+	// BlockedUpdatesOff means an "off" policy disables updates entirely.
+	BlockedUpdatesOff
+)
+
+func (r RolloutBlockReason) String() string {
+	switch r {
+	case BlockedTooOld:
+		return "remote version is below the configured floor"
+	case BlockedNotPinned:
+		return "remote version does not match the pinned version"
+	case BlockedUpdatesOff:
+		return "updates are disabled by policy"
+	default: // BlockedTooNew
+		return "remote version moves ahead further than the policy allows in one step"
+	}
+}
+
+// TooNewError is returned by Query whenever RolloutPolicy refuses to apply
+// a discovered update, not only when the remote is literally too new:
+// Reason says which of the policy's guardrails actually fired, so callers
+// building a reconciliation decision (and logs/telemetry) don't read "too
+// new" for what might be a pin mismatch or a disabled-updates policy.
+type TooNewError struct {
+	Current string
+	Remote  string
+	Policy  RolloutPolicy
+	Reason  RolloutBlockReason
+}
+
+func (e *TooNewError) Error() string {
+	return fmt.Sprintf("autoupdate: refusing to update %s -> %s: %s", e.Current, e.Remote, e.Reason)
+}
+
+// Allows reports whether policy permits moving from current to remote.
+// It never makes a network call.
+func (p RolloutPolicy) Allows(current, remote string) bool {
+	allowed, _ := p.evaluate(current, remote)
+	return allowed
+}
+
+// evaluate is Allows plus, when it refuses, which guardrail fired.
+func (p RolloutPolicy) evaluate(current, remote string) (allowed bool, reason RolloutBlockReason) {
+	switch p.kind {
+	case rolloutOff:
+		return false, BlockedUpdatesOff
+	case rolloutPin:
+		if remote != p.version {
+			return false, BlockedNotPinned
+		}
+		return true, 0
+	case rolloutMin:
+		if VersionCompare(p.version, remote) == Lower {
+			return false, BlockedTooOld
+		}
+		return true, 0
+	case rolloutPath:
+		if minorDelta(current, remote) > p.steps {
+			return false, BlockedTooNew
+		}
+		return true, 0
+	default: // rolloutAuto
+		return true, 0
+	}
+}
+
+// minorDelta returns how many minor versions remote is ahead of current
+// within the same major version. Different major versions, or a remote minor
+// version below current's, are treated as an unbounded delta so a "path+N"
+// policy never lets a major bump or minor downgrade through by accident. A
+// remote in the same minor version as current gives 0, whatever its patch
+// number.
+func minorDelta(current, remote string) int {
+	curMajor, curMinor, ok1 := majorMinor(current)
+	remMajor, remMinor, ok2 := majorMinor(remote)
+	if !ok1 || !ok2 || curMajor != remMajor || remMinor < curMinor {
+		return 1 << 30
+	}
+	return remMinor - curMinor
+}
+
+func majorMinor(v string) (major, minor int, ok bool) {
+	v = strings.TrimPrefix(v, "v")
+	parts := strings.SplitN(v, ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, false
+	}
+	major, err1 := strconv.Atoi(parts[0])
+	minor, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
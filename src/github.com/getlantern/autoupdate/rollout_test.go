@@ -0,0 +1,79 @@
+package autoupdate
+
+import "testing"
+
+func TestParseRolloutPolicy(t *testing.T) {
+	cases := []struct {
+		in      string
+		wantErr bool
+	}{
+		{"", false},
+		{"auto", false},
+		{"off", false},
+		{"min=v1.2.3", false},
+		{"pin=v1.2.3", false},
+		{"path+2", false},
+		{"path+-1", true},
+		{"path+nope", true},
+		{"bogus", true},
+	}
+
+	for _, c := range cases {
+		_, err := ParseRolloutPolicy(c.in)
+		if (err != nil) != c.wantErr {
+			t.Errorf("ParseRolloutPolicy(%q): err = %v, wantErr = %v", c.in, err, c.wantErr)
+		}
+	}
+}
+
+func TestRolloutPolicyAllows(t *testing.T) {
+	cases := []struct {
+		name    string
+		policy  RolloutPolicy
+		current string
+		remote  string
+		allowed bool
+		reason  RolloutBlockReason
+	}{
+		{"auto allows upgrade", AutoRollout(), "v1.0.0", "v2.0.0", true, 0},
+		{"off blocks everything", OffRollout(), "v1.0.0", "v2.0.0", false, BlockedUpdatesOff},
+		{"min allows at floor", MinRollout("v1.5.0"), "v1.0.0", "v1.5.0", true, 0},
+		{"min allows above floor", MinRollout("v1.5.0"), "v1.0.0", "v2.0.0", true, 0},
+		{"min blocks below floor", MinRollout("v1.5.0"), "v1.0.0", "v1.4.0", false, BlockedTooOld},
+		{"pin allows exact match", PinRollout("v1.5.0"), "v1.0.0", "v1.5.0", true, 0},
+		{"pin blocks mismatch", PinRollout("v1.5.0"), "v1.0.0", "v1.6.0", false, BlockedNotPinned},
+		{"path allows within budget", PathRollout(2), "v1.1.0", "v1.3.0", true, 0},
+		{"path blocks past budget", PathRollout(2), "v1.1.0", "v1.5.0", false, BlockedTooNew},
+		{"path blocks across major", PathRollout(2), "v1.9.0", "v2.0.0", false, BlockedTooNew},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.policy.Allows(c.current, c.remote); got != c.allowed {
+				t.Fatalf("Allows(%q, %q) = %v, want %v", c.current, c.remote, got, c.allowed)
+			}
+			if _, reason := c.policy.evaluate(c.current, c.remote); !c.allowed && reason != c.reason {
+				t.Fatalf("evaluate(%q, %q) reason = %v, want %v", c.current, c.remote, reason, c.reason)
+			}
+		})
+	}
+}
+
+func TestMinorDelta(t *testing.T) {
+	cases := []struct {
+		current, remote string
+		want            int
+	}{
+		{"v1.0.0", "v1.2.0", 2},
+		{"v1.0.0", "v1.0.0", 0},
+		{"v1.2.0", "v1.0.0", 1 << 30}, // remote older than current: unbounded
+		{"v1.0.0", "v2.0.0", 1 << 30}, // different major: unbounded
+		{"vX.Y.Z", "v1.0.0", 1 << 30}, // unparseable: unbounded
+	}
+
+	for _, c := range cases {
+		if got := minorDelta(c.current, c.remote); got != c.want {
+			t.Errorf("minorDelta(%q, %q) = %d, want %d", c.current, c.remote, got, c.want)
+		}
+	}
+}
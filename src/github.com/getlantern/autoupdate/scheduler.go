@@ -0,0 +1,112 @@
+package autoupdate
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Scheduler decides how long to wait between update checks. It is consulted
+// after every check, success or failure, so implementations can back off
+// when the server is unreachable and reset once things are healthy again.
+type Scheduler interface {
+	// Next returns how long to wait before the next check. ok reports
+	// whether the previous check (if any) succeeded; it is true before the
+	// first check.
+	Next(ok bool) time.Duration
+}
+
+// FixedIntervalScheduler waits the same amount of time between checks,
+// regardless of success or failure. This matches AutoUpdate's original
+// hardcoded behavior.
+type FixedIntervalScheduler struct {
+	Interval time.Duration
+}
+
+// NewFixedIntervalScheduler creates a Scheduler that always waits interval
+// between checks.
+func NewFixedIntervalScheduler(interval time.Duration) *FixedIntervalScheduler {
+	return &FixedIntervalScheduler{Interval: interval}
+}
+
+// Next implements Scheduler.
+func (s *FixedIntervalScheduler) Next(ok bool) time.Duration {
+	return s.Interval
+}
+
+// ExponentialBackoffScheduler grows the wait after each consecutive failure
+// using decorrelated jitter (a random wait between BaseInterval and three
+// times the previous wait), up to MaxInterval, so that many clients don't
+// retry in lockstep. A success resets the wait back to BaseInterval.
+type ExponentialBackoffScheduler struct {
+	BaseInterval time.Duration
+	MaxInterval  time.Duration
+
+	current time.Duration
+}
+
+// NewExponentialBackoffScheduler creates a Scheduler starting at base and
+// capped at max.
+func NewExponentialBackoffScheduler(base, max time.Duration) *ExponentialBackoffScheduler {
+	return &ExponentialBackoffScheduler{
+		BaseInterval: base,
+		MaxInterval:  max,
+	}
+}
+
+// Next implements Scheduler.
+func (s *ExponentialBackoffScheduler) Next(ok bool) time.Duration {
+	if ok || s.current == 0 {
+		s.current = s.BaseInterval
+		return s.current
+	}
+
+	// Decorrelated jitter: next = random between base and 3x the previous
+	// wait, capped at max. See AWS's "Exponential Backoff And Jitter".
+	next := s.BaseInterval + time.Duration(rand.Int63n(int64(s.current)*3-int64(s.BaseInterval)+1))
+	if next > s.MaxInterval {
+		next = s.MaxInterval
+	}
+	s.current = next
+	return s.current
+}
+
+// EventType identifies what happened during an update cycle.
+type EventType int
+
+const (
+	// CheckStarted fires right before a Source is queried for updates.
+	CheckStarted EventType = iota
+	// CheckFailed fires when a check returns an error.
+	CheckFailed
+	// UpdateAvailable fires when a check finds a newer version.
+	UpdateAvailable
+	// PatchApplied fires when an update was applied successfully.
+	PatchApplied
+	// PatchFailed fires when applying an available update failed.
+	PatchFailed
+	// PolicyBlocked fires when an update was found but RolloutPolicy
+	// refused to apply it (Query returned a *TooNewError).
+	PolicyBlocked
+	// FullBinaryFallback fires when a bsdiff patch couldn't be applied and
+	// the full binary from Config.FullBinaryURL was applied in its place.
+	FullBinaryFallback
+)
+
+// Event reports a single step of the update loop, for callers that want
+// structured telemetry instead of parsing UpdatedTo or logs.
+type Event struct {
+	Type    EventType
+	Version string
+	Err     error
+}
+
+func (a *AutoUpdate) emit(events chan<- Event, evt Event) {
+	if events == nil {
+		return
+	}
+	select {
+	case events <- evt:
+	default:
+		log.Debugf("autoupdate: dropping event %v, no receiver ready", evt.Type)
+	}
+}
@@ -0,0 +1,59 @@
+package autoupdate
+
+import "testing"
+
+func TestFixedIntervalSchedulerAlwaysReturnsInterval(t *testing.T) {
+	s := NewFixedIntervalScheduler(7)
+	for _, ok := range []bool{true, false, false, true} {
+		if got := s.Next(ok); got != 7 {
+			t.Fatalf("Next(%v) = %v, want 7", ok, got)
+		}
+	}
+}
+
+func TestExponentialBackoffSchedulerResetsOnSuccess(t *testing.T) {
+	s := NewExponentialBackoffScheduler(10, 1000)
+
+	if got := s.Next(true); got != 10 {
+		t.Fatalf("Next(true) on fresh scheduler = %v, want base 10", got)
+	}
+
+	for i := 0; i < 5; i++ {
+		s.Next(false)
+	}
+	if s.current <= 10 {
+		t.Fatalf("current = %v after repeated failures, want > base", s.current)
+	}
+
+	if got := s.Next(true); got != 10 {
+		t.Fatalf("Next(true) after failures = %v, want reset to base 10", got)
+	}
+}
+
+func TestExponentialBackoffSchedulerStaysWithinBounds(t *testing.T) {
+	s := NewExponentialBackoffScheduler(10, 100)
+
+	for i := 0; i < 50; i++ {
+		got := s.Next(false)
+		if got < 10 || got > 100 {
+			t.Fatalf("Next(false) = %v, want within [base=10, max=100]", got)
+		}
+	}
+}
+
+func TestExponentialBackoffSchedulerGrows(t *testing.T) {
+	s := NewExponentialBackoffScheduler(10, 1<<30)
+
+	prev := s.Next(false)
+	grew := false
+	for i := 0; i < 20; i++ {
+		next := s.Next(false)
+		if next > prev {
+			grew = true
+		}
+		prev = next
+	}
+	if !grew {
+		t.Fatal("expected current to grow across repeated failures at least once")
+	}
+}
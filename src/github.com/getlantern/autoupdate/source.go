@@ -0,0 +1,31 @@
+package autoupdate
+
+import (
+	"github.com/getlantern/go-update/check"
+)
+
+// Source looks up the latest available update for the running binary. It is
+// the seam between AutoUpdate's bookkeeping (current version, patch
+// application, scheduling) and wherever update metadata actually lives.
+// urlSource (the original check-URL behavior) and GitHubReleasesSource both
+// satisfy it, and callers can supply their own.
+type Source interface {
+	// Check looks up the latest release for appVersion and returns a
+	// check.Result describing the patch to apply, or a nil result if
+	// appVersion is already current.
+	Check(appVersion string) (*check.Result, error)
+}
+
+// urlSource is the default Source: it asks Config.URL for an update using
+// go-update's check protocol, exactly as AutoUpdate did before Source
+// existed. It advertises the patch formats this client supports, but
+// always applies bsdiff; if the fetched patch fails to apply,
+// AutoUpdate.loop falls back to a full-binary replacement using
+// Config.FullBinaryURL.
+type urlSource struct {
+	cfg *Config
+}
+
+func (s *urlSource) Check(appVersion string) (*check.Result, error) {
+	return checkURL(s.cfg, appVersion)
+}